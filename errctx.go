@@ -5,7 +5,9 @@ package errctx
 import (
 	"context"
 	"fmt"
+	"io"
 	"path"
+	"reflect"
 	"runtime"
 )
 
@@ -16,8 +18,13 @@ type errctx struct {
 	ctx map[interface{}]interface{}
 }
 
-// Error implements the error interface
+// Error implements the error interface. If the error was produced (or passed
+// through) a call to Wrap/Wrapf, the accumulated wrap message is prefixed
+// onto the base error's message.
 func (ec errctx) Error() string {
+	if msg, ok := ec.ctx[msgKey(0)].(string); ok && msg != "" {
+		return msg + ": " + ec.err.Error()
+	}
 	return ec.err.Error()
 }
 
@@ -33,6 +40,38 @@ func (ec errctx) Is(err error) bool {
 	return err == ec.err || Base(err) == ec.err
 }
 
+// Format implements fmt.Formatter, in the style of pkg/errors. %s and %v
+// print the same thing as Error(). %q prints a quoted Error(). %+v prints
+// Error() followed by the source line reported by Line (if any; this is the
+// Mark line, or else the top frame of a captured stack), each frame of a
+// captured stack (if any), and finally the sorted KV pairs from ErrKV, one
+// per line.
+func (ec errctx) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			io.WriteString(s, ec.Error())
+			if line, ok := Line(ec); ok {
+				fmt.Fprintf(s, "\n%s", line)
+			}
+			if frames, ok := Stack(ec); ok {
+				for _, f := range frames {
+					fmt.Fprintf(s, "\n%s", frameString(f))
+				}
+			}
+			for _, kv := range ErrKV(ec).StringSlice() {
+				fmt.Fprintf(s, "\n%s=%s", kv[0], kv[1])
+			}
+			return
+		}
+		io.WriteString(s, ec.Error())
+	case 's':
+		io.WriteString(s, ec.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", ec.Error())
+	}
+}
+
 // Base returns the underlying error object that was prevoiusly wrapped in a
 // call to Set. If the error did not come from Set it is returned as-is.
 func Base(err error) error {
@@ -77,7 +116,15 @@ func Set(err error, kvs ...interface{}) error {
 // Get retrieves the value associated with the key by a previous call to Set,
 // which this error should have been returned from. Returns nil if the key isn't
 // set, or if the error wasn't previously wrapped by Set at all.
+//
+// If err came from Join, Get aggregates the key across all of its children:
+// if every child which has the key agrees on its value that value is
+// returned, otherwise a []interface{} of the distinct values is returned, in
+// child order.
 func Get(err error, k interface{}) interface{} {
+	if j, ok := err.(joinError); ok {
+		return getJoined(j, k)
+	}
 	ec, ok := err.(errctx)
 	if !ok {
 		return nil
@@ -85,6 +132,37 @@ func Get(err error, k interface{}) interface{} {
 	return ec.ctx[k]
 }
 
+func getJoined(j joinError, k interface{}) interface{} {
+	var vals []interface{}
+	for _, err := range j.errs {
+		v := Get(err, k)
+		if v == nil {
+			continue
+		}
+		// values stored under a key aren't guaranteed to be comparable (e.g.
+		// kvKey(0) holds a KVer func value, stackKey(0) holds a []uintptr),
+		// so reflect.DeepEqual is used here instead of == to avoid a panic.
+		dup := false
+		for _, existing := range vals {
+			if reflect.DeepEqual(existing, v) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			vals = append(vals, v)
+		}
+	}
+	switch len(vals) {
+	case 0:
+		return nil
+	case 1:
+		return vals[0]
+	default:
+		return vals
+	}
+}
+
 // Mark records the filename and line number that called Mark and sets it on
 // the error. Future calls to Mark will NOT overwrite the previous line.
 func Mark(err error) error {
@@ -112,72 +190,231 @@ func MarkSkip(err error, skip int) error {
 }
 
 // Line returns the file and line number where Mark was first called on the
-// error and a boolean indicating if any line was found.
+// error and a boolean indicating if any line was found. If no Mark was ever
+// made but a stack was captured via MarkStack, the source line of the
+// topmost frame of that stack is used instead. If err came from Join, the
+// first non-empty line found among its children (in order) is returned.
 func Line(err error) (string, bool) {
+	if j, ok := err.(joinError); ok {
+		for _, child := range j.errs {
+			if l, ok := Line(child); ok {
+				return l, true
+			}
+		}
+		return "", false
+	}
 	ec, ok := err.(errctx)
 	if !ok {
 		return "", false
 	}
-	s, ok := ec.ctx[sourceKey(0)].(string)
-	return s, ok
+	if s, ok := ec.ctx[sourceKey(0)].(string); ok {
+		return s, true
+	}
+	if frames, ok := Stack(err); ok && len(frames) > 0 {
+		f := frames[0]
+		return fmt.Sprintf("%s:%d", path.Base(f.File), f.Line), true
+	}
+	return "", false
+}
+
+// maxStackDepth is the maximum number of program counters MarkStack will
+// capture from the call stack.
+const maxStackDepth = 32
+
+type stackKey int
+
+// MarkStack records the full call stack at the point it's called and sets it
+// on the error, in the form of a slice of program counters (cheap to capture,
+// since no file/line/function resolution happens until Stack is called).
+// Future calls to MarkStack will NOT overwrite a previously captured stack.
+func MarkStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	if Get(err, stackKey(0)) != nil {
+		return err
+	}
+	pcs := make([]uintptr, maxStackDepth)
+	// skip runtime.Callers and MarkStack itself
+	n := runtime.Callers(2, pcs)
+	return Set(err, stackKey(0), pcs[:n])
+}
+
+// Stack returns the call stack captured by a previous call to MarkStack,
+// resolved into runtime.Frame values, and a boolean indicating if a stack was
+// found. If err came from Join, the first non-empty stack found among its
+// children (in order) is returned.
+func Stack(err error) ([]runtime.Frame, bool) {
+	if j, ok := err.(joinError); ok {
+		for _, child := range j.errs {
+			if frames, ok := Stack(child); ok {
+				return frames, true
+			}
+		}
+		return nil, false
+	}
+	pcsi := Get(err, stackKey(0))
+	if pcsi == nil {
+		return nil, false
+	}
+	pcs, ok := pcsi.([]uintptr)
+	if !ok || len(pcs) == 0 {
+		return nil, false
+	}
+	frames := runtime.CallersFrames(pcs)
+	out := make([]runtime.Frame, 0, len(pcs))
+	for {
+		frame, more := frames.Next()
+		out = append(out, frame)
+		if !more {
+			break
+		}
+	}
+	return out, true
+}
+
+// frameString formats a runtime.Frame as "pkg/file.go:line func", matching
+// the format used by ErrKV's "stack" field.
+func frameString(f runtime.Frame) string {
+	dir, file := path.Split(f.File)
+	if pkg := path.Base(dir); pkg != "" && pkg != "." {
+		file = pkg + "/" + file
+	}
+	return fmt.Sprintf("%s:%d %s", file, f.Line, f.Function)
 }
 
 // brought in from go-llog to combine erroring functionality to use in different loggers
 
 type kvKey int
 
-// ErrWithKV embeds the merging of a set of KVs into an error and Marks the
+// ErrWithKV embeds the merging of a set of KVers into an error and Marks the
 // function for convenience, returning a new error instance. If the error
 // already has a KV embedded in it then the returned error will have the
-// merging of them all.
-func ErrWithKV(err error, kvs ...KV) error {
+// merging of them all. The merge itself is not performed until ErrKV is
+// called on the resulting error, so a Lazy KVer passed in here won't have its
+// underlying function called unless/until the error is actually logged.
+func ErrWithKV(err error, kvs ...KVer) error {
 	if err == nil {
 		return nil
 	}
-	kv := Merge(kvs...)
-	existingKV := Get(err, kvKey(0))
-	if existingKV != nil {
-		kv = Merge(existingKV.(KV), kv)
+	all := snapshotKVers(kvs)
+	if existing := existingKVer(err); existing != nil {
+		all = append([]KVer{existing}, all...)
+	}
+	return MarkSkip(Set(err, kvKey(0), mergeLazy(all)), 1)
+}
+
+// existingKVer returns the KVer already embedded under kvKey(0) in err, or
+// nil if there isn't one. This can't just be Get(err, kvKey(0)), because
+// Get's generic join handling aggregates conflicting values across children
+// into a []interface{}, which isn't a KVer; here, if err came from Join, the
+// KVers of all of its children (recursively) are merged into one instead.
+func existingKVer(err error) KVer {
+	if j, ok := err.(joinError); ok {
+		var kvers []KVer
+		for _, child := range j.errs {
+			if kver := existingKVer(child); kver != nil {
+				kvers = append(kvers, kver)
+			}
+		}
+		if len(kvers) == 0 {
+			return nil
+		}
+		return mergeLazy(kvers)
+	}
+	ec, ok := err.(errctx)
+	if !ok {
+		return nil
+	}
+	kvi, ok := ec.ctx[kvKey(0)]
+	if !ok {
+		return nil
+	}
+	return kvi.(KVer)
+}
+
+// snapshotKVers copies any plain KV among kvers so that later mutations of
+// the caller's map don't affect the (possibly still-unevaluated) merge. KVers
+// which aren't plain KVs, e.g. ones from Lazy, are left untouched, since the
+// whole point of those is to defer evaluation.
+func snapshotKVers(kvers []KVer) []KVer {
+	out := make([]KVer, len(kvers))
+	for i, kver := range kvers {
+		if kv, ok := kver.(KV); ok {
+			out[i] = kv.Copy()
+			continue
+		}
+		out[i] = kver
 	}
-	return MarkSkip(Set(err, kvKey(0), kv), 1)
+	return out
 }
 
 // ErrKV returns a copy of the KV embedded in the error by ErrWithKV as well as
 // any line from Mark as the key "source" if "source" wasn't already set.
 // Returns empty KV if no KV was previously embedded and no line was marked.
 // Will automatically set the "err" field on the returned KV as well.
+//
+// If err came from Join, the returned KV is the merge of the ErrKV of all of
+// its children (right-wins, same as Merge), with "err" set to the join's own
+// Error() message.
 func ErrKV(err error) KV {
 	if err == nil {
 		return KV{}
 	}
+	if j, ok := err.(joinError); ok {
+		kvs := make([]KV, len(j.errs))
+		for i, child := range j.errs {
+			kvs[i] = ErrKV(child)
+		}
+		return MergeKV(kvs...).Set("err", err.Error())
+	}
 	kvi := Get(err, kvKey(0))
+	var kv KV
 	if kvi == nil {
-		kvi = KV{}
+		kv = KV{}
+	} else {
+		kv = kvi.(KVer).KV()
 	}
-	kv := kvi.(KV).Set("err", err.Error())
+	kv = kv.Set("err", err.Error())
 	if line, ok := Line(err); ok && kv["source"] == nil {
 		kv = kv.Set("source", line)
 	}
+	if frames, ok := Stack(err); ok && kv["stack"] == nil {
+		strs := make([]string, len(frames))
+		for i, f := range frames {
+			strs[i] = frameString(f)
+		}
+		kv = kv.Set("stack", strs)
+	}
 	return kv
 }
 
-// CtxWithKV embeds a KV into a Context, returning a new Context instance. If
-// the Context already has a KV embedded in it then the returned context's KV
-// will be the merging of the two.
-func CtxWithKV(ctx context.Context, kvs ...KV) context.Context {
-	kv := Merge(kvs...)
-	existingKV := ctx.Value(kvKey(0))
-	if existingKV != nil {
-		kv = Merge(existingKV.(KV), kv)
+// CtxWithKV embeds a KVer into a Context, returning a new Context instance.
+// If the Context already has a KV embedded in it then the returned context's
+// KV will be the merging of the two. As with ErrWithKV, the merge isn't
+// performed until CtxKV is called, so a Lazy KVer won't be evaluated unless
+// the Context is actually logged.
+func CtxWithKV(ctx context.Context, kvs ...KVer) context.Context {
+	all := snapshotKVers(kvs)
+	if existing := ctx.Value(kvKey(0)); existing != nil {
+		all = append([]KVer{existing.(KVer)}, all...)
 	}
-	return context.WithValue(ctx, kvKey(0), kv)
+	return context.WithValue(ctx, kvKey(0), mergeLazy(all))
 }
 
 // CtxKV returns a copy of the KV embedded in the Context by CtxWithKV
 func CtxKV(ctx context.Context) KV {
-	kv := ctx.Value(kvKey(0))
-	if kv == nil {
+	kvi := ctx.Value(kvKey(0))
+	if kvi == nil {
 		return KV{}
 	}
-	return kv.(KV)
+	return kvi.(KVer).KV()
+}
+
+// mergeLazy returns a KVer whose KV() merges the KVs of all of kvers,
+// right-wins, without evaluating any of them until it's itself called.
+func mergeLazy(kvers []KVer) KVer {
+	return KVerFunc(func() KV {
+		return Merge(kvers...)
+	})
 }
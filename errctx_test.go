@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"runtime"
+	"strings"
 	. "testing"
 
 	"github.com/stretchr/testify/assert"
@@ -79,6 +80,34 @@ func TestMark(t *T) {
 	assert.Equal(t, fmt.Sprintf("errctx_test.go:%d", ln-1), l)
 }
 
+func TestMarkStack(t *T) {
+	err := errors.New("baz")
+
+	frames, ok := Stack(err)
+	assert.False(t, ok)
+	assert.Empty(t, frames)
+
+	err = func() error {
+		return MarkStack(errors.New("baz"))
+	}()
+	frames, ok = Stack(err)
+	require.True(t, ok)
+	require.NotEmpty(t, frames)
+	assert.Contains(t, frames[0].Function, "TestMarkStack")
+
+	// calling it again shouldn't do anything
+	err2 := MarkStack(err)
+	frames2, ok := Stack(err2)
+	require.True(t, ok)
+	assert.Equal(t, frames, frames2)
+
+	// Line should fall back to the top frame of the stack when no explicit
+	// Mark was made
+	l, ok := Line(err)
+	assert.True(t, ok)
+	assert.Equal(t, fmt.Sprintf("errctx_test.go:%d", frames[0].Line), l)
+}
+
 func TestErrKV(t *T) {
 	err := errors.New("foo")
 	assert.Equal(t, KV{"err": err.Error()}, ErrKV(err))
@@ -86,30 +115,73 @@ func TestErrKV(t *T) {
 	kv := KV{"a": "a"}
 	err2 := ErrWithKV(err, kv)
 	assert.Equal(t, KV{"err": err.Error()}, ErrKV(err))
-	assert.Equal(t, KV{"err": err2.Error(), "a": "a", "source": "errctx_test.go:87"}, ErrKV(err2))
+	assert.Equal(t, KV{"err": err2.Error(), "a": "a", "source": "errctx_test.go:116"}, ErrKV(err2))
 
 	// changing the kv now shouldn't do anything
 	kv["a"] = "b"
 	assert.Equal(t, KV{"err": err.Error()}, ErrKV(err))
-	assert.Equal(t, KV{"err": err2.Error(), "a": "a", "source": "errctx_test.go:87"}, ErrKV(err2))
+	assert.Equal(t, KV{"err": err2.Error(), "a": "a", "source": "errctx_test.go:116"}, ErrKV(err2))
 
 	// a new ErrWithKV shouldn't affect the previous one
 	err3 := ErrWithKV(err2, KV{"b": "b"})
 	assert.Equal(t, KV{"err": err.Error()}, ErrKV(err))
-	assert.Equal(t, KV{"err": err2.Error(), "a": "a", "source": "errctx_test.go:87"}, ErrKV(err2))
-	assert.Equal(t, KV{"err": err3.Error(), "a": "a", "b": "b", "source": "errctx_test.go:87"}, ErrKV(err3))
+	assert.Equal(t, KV{"err": err2.Error(), "a": "a", "source": "errctx_test.go:116"}, ErrKV(err2))
+	assert.Equal(t, KV{"err": err3.Error(), "a": "a", "b": "b", "source": "errctx_test.go:116"}, ErrKV(err3))
 
 	// make sure precedence works
 	err4 := ErrWithKV(err3, KV{"b": "bb"})
 	assert.Equal(t, KV{"err": err.Error()}, ErrKV(err))
-	assert.Equal(t, KV{"err": err2.Error(), "a": "a", "source": "errctx_test.go:87"}, ErrKV(err2))
-	assert.Equal(t, KV{"err": err3.Error(), "a": "a", "b": "b", "source": "errctx_test.go:87"}, ErrKV(err3))
-	assert.Equal(t, KV{"err": err4.Error(), "a": "a", "b": "bb", "source": "errctx_test.go:87"}, ErrKV(err4))
+	assert.Equal(t, KV{"err": err2.Error(), "a": "a", "source": "errctx_test.go:116"}, ErrKV(err2))
+	assert.Equal(t, KV{"err": err3.Error(), "a": "a", "b": "b", "source": "errctx_test.go:116"}, ErrKV(err3))
+	assert.Equal(t, KV{"err": err4.Error(), "a": "a", "b": "bb", "source": "errctx_test.go:116"}, ErrKV(err4))
 
 	err = nil
 	assert.Equal(t, KV{}, ErrKV(err))
 }
 
+func TestErrWithKVLazy(t *T) {
+	calls := 0
+	err := ErrWithKV(errors.New("foo"), Lazy(func() KV {
+		calls++
+		return KV{"expensive": "computed"}
+	}))
+	assert.Equal(t, 0, calls)
+
+	kv := ErrKV(err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "computed", kv["expensive"])
+}
+
+func TestFormat(t *T) {
+	err := errors.New("foo")
+	assert.Equal(t, "foo", fmt.Sprintf("%v", err))
+
+	err2 := ErrWithKV(err, KV{"a": "a"})
+	assert.Equal(t, err2.Error(), fmt.Sprintf("%v", err2))
+	assert.Equal(t, err2.Error(), fmt.Sprintf("%s", err2))
+	assert.Equal(t, fmt.Sprintf("%q", err2.Error()), fmt.Sprintf("%q", err2))
+
+	line, ok := Line(err2)
+	require.True(t, ok)
+	want := err2.Error() + "\n" + line + "\na=a\nerr=" + err2.Error() + "\nsource=" + line
+	assert.Equal(t, want, fmt.Sprintf("%+v", err2))
+
+	// %+v should also print each frame of a captured stack
+	err3 := MarkStack(errors.New("bar"))
+	frames, ok := Stack(err3)
+	require.True(t, ok)
+	require.NotEmpty(t, frames)
+
+	out := fmt.Sprintf("%+v", err3)
+	assert.True(t, strings.HasPrefix(out, err3.Error()))
+	for _, f := range frames {
+		assert.Contains(t, out, frameString(f))
+	}
+	line3, ok := Line(err3)
+	require.True(t, ok)
+	assert.Contains(t, out, "source="+line3)
+}
+
 // copied from go-llog errctx_test.go
 
 func TestCtxKV(t *T) {
@@ -0,0 +1,58 @@
+package errctx
+
+import "strings"
+
+// joinError is the error type returned by Join. It implements the standard
+// library's multi-error convention (Unwrap() []error) so errors.Is/errors.As
+// walk all of its children.
+type joinError struct {
+	errs []error
+}
+
+// Join returns an error that wraps the given errors, in the style of the
+// standard library's errors.Join. Nil errors are dropped, and Join returns
+// nil if every error passed in is nil. The KV, source and stack attached to
+// the returned error (via ErrKV, Line and Stack) are derived from its
+// children: see Errors, Get, Line and Stack for details of how each behaves
+// on a joined error.
+func Join(errs ...error) error {
+	nonNil := make([]error, 0, len(errs))
+	for _, err := range errs {
+		if err != nil {
+			nonNil = append(nonNil, err)
+		}
+	}
+	if len(nonNil) == 0 {
+		return nil
+	}
+	return joinError{errs: nonNil}
+}
+
+// Error implements the error interface, joining the messages of all the
+// child errors with a newline, matching errors.Join's behavior.
+func (j joinError) Error() string {
+	var b strings.Builder
+	for i, err := range j.errs {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the child errors which were passed into Join. Necessary for
+// errors.Is/errors.As to walk into them.
+func (j joinError) Unwrap() []error {
+	return j.errs
+}
+
+// Errors returns the child errors attached to err by a previous call to
+// Join. Returns nil if err didn't come from Join.
+func Errors(err error) []error {
+	j, ok := err.(joinError)
+	if !ok {
+		return nil
+	}
+	return j.errs
+}
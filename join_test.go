@@ -0,0 +1,70 @@
+package errctx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoin(t *testing.T) {
+	assert.Nil(t, Join())
+	assert.Nil(t, Join(nil, nil))
+
+	err1 := errors.New("one")
+	err2 := errors.New("two")
+
+	j := Join(err1, nil, err2)
+	assert.Equal(t, "one\ntwo", j.Error())
+	assert.True(t, errors.Is(j, err1))
+	assert.True(t, errors.Is(j, err2))
+	assert.Equal(t, []error{err1, err2}, Errors(j))
+	assert.Equal(t, j, Base(j))
+}
+
+func TestJoinErrKV(t *testing.T) {
+	err1 := ErrWithKV(errors.New("one"), KV{"a": "a", "shared": "x"})
+	err2 := ErrWithKV(errors.New("two"), KV{"b": "b", "shared": "y"})
+
+	j := Join(err1, err2)
+	kv := ErrKV(j)
+	assert.Equal(t, "a", kv["a"])
+	assert.Equal(t, "b", kv["b"])
+	assert.Equal(t, "y", kv["shared"]) // right-wins
+	assert.Equal(t, j.Error(), kv["err"])
+
+	// Line/Stack return the first one found among children
+	err3 := Mark(errors.New("three"))
+	j2 := Join(errors.New("zero"), err3)
+	l, ok := Line(j2)
+	assert.True(t, ok)
+	assert.Equal(t, l, func() string { s, _ := Line(err3); return s }())
+
+	// Get aggregates conflicting values across children
+	e1 := Set(errors.New("a"), key(0), "x")
+	e2 := Set(errors.New("b"), key(0), "y")
+	e3 := Set(errors.New("c"), key(0), "x")
+	agg := Join(e1, e2, e3)
+	assert.Equal(t, []interface{}{"x", "y"}, Get(agg, key(0)))
+}
+
+// fan-out workers attaching KV and then getting joined together, the
+// motivating use case for Join, shouldn't panic, and the resulting error
+// should carry forward the merge of all the children's KV plus the new one.
+// The per-child KV stored under kvKey(0) is a non-comparable KVer func
+// value, so ErrWithKV can't find the existing KV to merge with via Get's
+// generic (and, for arbitrary keys, still correct) multi-value aggregation.
+func TestJoinGetNonComparable(t *testing.T) {
+	w1 := ErrWithKV(errors.New("worker1 failed"), KV{"worker": 1})
+	w2 := ErrWithKV(errors.New("worker2 failed"), KV{"worker": 2})
+	joined := Join(w1, w2)
+
+	var result error
+	assert.NotPanics(t, func() {
+		result = ErrWithKV(joined, KV{"phase": "fanout"})
+	})
+
+	kv := ErrKV(result)
+	assert.Equal(t, "fanout", kv["phase"])
+	assert.Equal(t, 2, kv["worker"]) // right-wins between w1 and w2
+}
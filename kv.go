@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 )
 
 // KV is used to provide context to a log entry in the form of a dynamic set of
@@ -20,10 +21,71 @@ func (kv KV) Copy() KV {
 	return nkv
 }
 
-// Merge takes in multiple KVs and returns a single KV which is the union of all
-// the passed in ones. Key/vals on the rightmost of the set take precedence over
+// KVer is implemented by anything which can supply a KV, allowing non-map
+// types (structs, protobuf messages, domain entities) to provide log-friendly
+// context without forcing allocation of a KV at every call site. KV itself
+// trivially satisfies KVer.
+type KVer interface {
+	KV() KV
+}
+
+// KV implements KVer, simply returning itself.
+func (kv KV) KV() KV {
+	return kv
+}
+
+type kverFunc func() KV
+
+// KV implements KVer.
+func (f kverFunc) KV() KV {
+	return f()
+}
+
+// KVerFunc adapts a function into a KVer. Unlike Lazy, the function is
+// called fresh every time KV is called on the result.
+func KVerFunc(f func() KV) KVer {
+	return kverFunc(f)
+}
+
+type lazyKVer struct {
+	once sync.Once
+	fn   func() KV
+	kv   KV
+}
+
+// KV implements KVer, calling fn and caching its result the first time it's
+// called.
+func (l *lazyKVer) KV() KV {
+	l.once.Do(func() {
+		l.kv = l.fn()
+	})
+	return l.kv
+}
+
+// Lazy adapts a function into a KVer which only calls that function (and
+// only once, caching the result) the first time KV is called on it. This is
+// useful for attaching expensive-to-compute or expensive-to-stringify values
+// to an error or Context on a hot path which may never actually be logged;
+// with ErrWithKV/CtxWithKV the function won't run until ErrKV/CtxKV is
+// called.
+func Lazy(f func() KV) KVer {
+	return &lazyKVer{fn: f}
+}
+
+// Merge takes in multiple KVers and returns a single KV which is the union of
+// all of their KVs. Key/vals on the rightmost of the set take precedence over
 // conflicting ones to the left. This function will never return nil
-func Merge(kvs ...KV) KV {
+func Merge(kvers ...KVer) KV {
+	kvs := make([]KV, len(kvers))
+	for i := range kvers {
+		kvs[i] = kvers[i].KV()
+	}
+	return MergeKV(kvs...)
+}
+
+// MergeKV is like Merge but takes in KVs directly, for callers which already
+// have concrete KVs in hand and have no need for KVer's laziness.
+func MergeKV(kvs ...KV) KV {
 	kv := make(KV, len(kvs))
 	for i := range kvs {
 		for k, v := range kvs[i] {
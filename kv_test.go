@@ -19,3 +19,40 @@ func TestMerge(t *testing.T) {
 	assert.Equal(t, 3, len(output))
 	assert.Equal(t, KV{"key1": "value1", "key2": 2, "key3": false}, output)
 }
+
+func TestKVerFunc(t *testing.T) {
+	calls := 0
+	kver := KVerFunc(func() KV {
+		calls++
+		return KV{"n": calls}
+	})
+
+	assert.Equal(t, KV{"n": 1}, kver.KV())
+	assert.Equal(t, KV{"n": 2}, kver.KV())
+}
+
+func TestLazy(t *testing.T) {
+	calls := 0
+	kver := Lazy(func() KV {
+		calls++
+		return KV{"n": calls}
+	})
+	assert.Equal(t, 0, calls)
+
+	assert.Equal(t, KV{"n": 1}, kver.KV())
+	assert.Equal(t, KV{"n": 1}, kver.KV())
+	assert.Equal(t, 1, calls)
+}
+
+func TestMergeWithKVer(t *testing.T) {
+	calls := 0
+	lazy := Lazy(func() KV {
+		calls++
+		return KV{"b": 2}
+	})
+
+	assert.Equal(t, 0, calls)
+	output := Merge(KV{"a": 1}, lazy)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, KV{"a": 1, "b": 2}, output)
+}
@@ -0,0 +1,103 @@
+// Package slogctx bridges errctx into the standard library's log/slog
+// package, so errors and contexts carrying errctx KV data can be logged
+// through slog without manually calling errctx.ErrKV/CtxKV at every log
+// site.
+package slogctx
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"github.com/theoriginalstove/errctx"
+)
+
+// Handler wraps an slog.Handler, automatically promoting the KV attached (via
+// errctx) to any error attribute on a log record, as well as any KV attached
+// to the record's context, into slog.Attrs on the emitted record.
+type Handler struct {
+	inner slog.Handler
+}
+
+// New wraps inner in a Handler.
+func New(inner slog.Handler) *Handler {
+	return &Handler{inner: inner}
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler. It merges CtxAttrs(ctx) into the record,
+// and for every "error"-valued attribute already on the record it appends
+// ErrAttrs(err) as well.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	var errAttrs []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		if err, ok := a.Value.Any().(error); ok {
+			errAttrs = append(errAttrs, ErrAttrs(err)...)
+		}
+		return true
+	})
+	r.AddAttrs(CtxAttrs(ctx)...)
+	r.AddAttrs(errAttrs...)
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{inner: h.inner.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{inner: h.inner.WithGroup(name)}
+}
+
+// ErrAttrs converts the KV embedded in err by errctx.ErrWithKV (see
+// errctx.ErrKV) into slog.Attrs, using the value's own kind rather than
+// fmt.Sprint-ing everything into a string.
+func ErrAttrs(err error) []slog.Attr {
+	return kvAttrs(errctx.ErrKV(err))
+}
+
+// CtxAttrs converts the KV embedded in ctx by errctx.CtxWithKV (see
+// errctx.CtxKV) into slog.Attrs, using the value's own kind rather than
+// fmt.Sprint-ing everything into a string.
+func CtxAttrs(ctx context.Context) []slog.Attr {
+	return kvAttrs(errctx.CtxKV(ctx))
+}
+
+func kvAttrs(kv errctx.KV) []slog.Attr {
+	keys := make([]string, 0, len(kv))
+	for k := range kv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, len(keys))
+	for i, k := range keys {
+		attrs[i] = attrFor(k, kv[k])
+	}
+	return attrs
+}
+
+func attrFor(k string, v interface{}) slog.Attr {
+	switch val := v.(type) {
+	case string:
+		return slog.String(k, val)
+	case int:
+		return slog.Int(k, val)
+	case int64:
+		return slog.Int64(k, val)
+	case uint64:
+		return slog.Uint64(k, val)
+	case float64:
+		return slog.Float64(k, val)
+	case bool:
+		return slog.Bool(k, val)
+	default:
+		return slog.Any(k, val)
+	}
+}
@@ -0,0 +1,42 @@
+package slogctx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/theoriginalstove/errctx"
+)
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := New(slog.NewJSONHandler(&buf, nil))
+	log := slog.New(h)
+
+	err := errctx.ErrWithKV(errors.New("boom"), errctx.KV{"attempt": 3})
+	ctx := errctx.CtxWithKV(context.Background(), errctx.KV{"request_id": "abc"})
+
+	log.ErrorContext(ctx, "failed", "error", err)
+
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.Equal(t, "abc", out["request_id"])
+	assert.EqualValues(t, 3, out["attempt"])
+	assert.Equal(t, err.Error(), out["err"])
+}
+
+func TestErrAttrsAndCtxAttrs(t *testing.T) {
+	err := errctx.ErrWithKV(errors.New("boom"), errctx.KV{"n": 1, "s": "x"})
+	attrs := ErrAttrs(err)
+	assert.Contains(t, attrs, slog.Int("n", 1))
+	assert.Contains(t, attrs, slog.String("s", "x"))
+
+	ctx := errctx.CtxWithKV(context.Background(), errctx.KV{"s": "y"})
+	assert.Equal(t, []slog.Attr{slog.String("s", "y")}, CtxAttrs(ctx))
+}
@@ -0,0 +1,56 @@
+package errctx
+
+import "fmt"
+
+type msgKey int
+
+// Wrap returns a new error whose Error() is msg+": "+err.Error(), in the
+// style of pkg/errors' Wrap. The returned error carries forward everything
+// that was already attached to err via Set (including any KV from ErrWithKV
+// and the source line from Mark). Wrapping multiple times accumulates the
+// messages ("c: b: a: root"), but because Wrap goes through Set (which
+// always re-bases to the original root error, see Base), Unwrap walks
+// straight to that root in one hop rather than stepping through each
+// intermediate wrap layer the way pkg/errors' Unwrap does. Returns nil if
+// err is nil.
+func Wrap(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	if prev, ok := Get(err, msgKey(0)).(string); ok && prev != "" {
+		msg = msg + ": " + prev
+	}
+	return Set(err, msgKey(0), msg)
+}
+
+// Wrapf is like Wrap but formats its message according to a format
+// specifier.
+func Wrapf(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return Wrap(err, fmt.Sprintf(format, args...))
+}
+
+// Cause walks the error chain, via both Unwrap and the pkg/errors-style
+// Causer interface (Cause() error), returning the deepest error found. This
+// is provided for compatibility with code written against pkg/errors'
+// Cause; within this package Base does the same job.
+func Cause(err error) error {
+	for err != nil {
+		if c, ok := err.(interface{ Cause() error }); ok {
+			err = c.Cause()
+			continue
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			break
+		}
+		next := u.Unwrap()
+		if next == nil {
+			break
+		}
+		err = next
+	}
+	return err
+}
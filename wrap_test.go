@@ -0,0 +1,40 @@
+package errctx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrap(t *testing.T) {
+	err := errors.New("root")
+
+	assert.Nil(t, Wrap(nil, "nope"))
+
+	err1 := Wrap(err, "reading config")
+	assert.Equal(t, "reading config: root", err1.Error())
+	assert.Equal(t, err, Base(err1))
+	assert.True(t, errors.Is(err1, err))
+
+	err2 := Wrap(err1, "starting server")
+	assert.Equal(t, "starting server: reading config: root", err2.Error())
+	assert.Equal(t, err, Base(err2))
+	assert.True(t, errors.Is(err2, err))
+
+	err3 := Wrapf(err1, "starting %s", "server")
+	assert.Equal(t, "starting server: reading config: root", err3.Error())
+
+	// KV attached before wrapping should still be there after
+	err4 := ErrWithKV(err, KV{"a": "a"})
+	err5 := Wrap(err4, "reading config")
+	assert.Equal(t, "reading config: root", err5.Error())
+	assert.Equal(t, "a", ErrKV(err5)["a"])
+}
+
+func TestCause(t *testing.T) {
+	err := errors.New("root")
+	assert.Equal(t, err, Cause(err))
+	assert.Equal(t, err, Cause(Wrap(err, "context")))
+	assert.Equal(t, err, Cause(Wrap(Wrap(err, "a"), "b")))
+}